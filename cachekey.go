@@ -0,0 +1,79 @@
+package crossover_cache
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// varyRegistryPrefix namespaces the Redis keys that remember, per request,
+// which response header named the Vary values the cache key must include.
+const varyRegistryPrefix = "vary:"
+
+// baseCacheKey identifies a request's method, host and full URL, before
+// Vary is taken into account. req.URL carries no authority for server-side
+// requests, so req.Host is included explicitly, or two virtual hosts at the
+// same path would collide on one cache entry.
+func baseCacheKey(req *http.Request) string {
+	return req.Method + " " + req.Host + req.URL.String()
+}
+
+// scopedInvalidatePattern confines an X-Cache-Invalidate pattern to
+// req.Host, so one client can't purge another host's entries.
+func scopedInvalidatePattern(req *http.Request, pattern string) string {
+	return "* " + req.Host + pattern
+}
+
+// varyRegistryKey is where the Vary header names recorded for base are
+// stored, so a later request can compute its vary-aware cache key without
+// first round-tripping to the origin.
+func varyRegistryKey(base string) string {
+	return varyRegistryPrefix + base
+}
+
+// parseVaryHeader splits a Vary response header into header names, e.g.
+// "Accept-Encoding, Accept-Language" -> ["Accept-Encoding", "Accept-Language"].
+func parseVaryHeader(vary string) []string {
+	if vary == "" {
+		return nil
+	}
+	fields := strings.Split(vary, ",")
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if name := strings.TrimSpace(field); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// encodeVaryHeaders serializes header names for storage in the vary
+// registry.
+func encodeVaryHeaders(names []string) string {
+	return strings.Join(names, ",")
+}
+
+func decodeVaryHeaders(stored string) []string {
+	return parseVaryHeader(stored)
+}
+
+// cacheKeyWithVary extends base with the request's values for each header
+// listed in varyHeaders, sorted for a stable key regardless of the order
+// the origin listed them in its Vary response header.
+func cacheKeyWithVary(base string, varyHeaders []string, header http.Header) string {
+	if len(varyHeaders) == 0 {
+		return base
+	}
+	names := append([]string(nil), varyHeaders...)
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(base)
+	for _, name := range names {
+		b.WriteByte('|')
+		b.WriteString(strings.ToLower(name))
+		b.WriteByte('=')
+		b.WriteString(header.Get(name))
+	}
+	return b.String()
+}
@@ -0,0 +1,61 @@
+package crossover_cache
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestBaseCacheKeyIncludesHost(t *testing.T) {
+	reqA := &http.Request{Method: http.MethodGet, Host: "a.example.com", URL: &url.URL{Path: "/p"}}
+	reqB := &http.Request{Method: http.MethodGet, Host: "b.example.com", URL: &url.URL{Path: "/p"}}
+
+	if baseCacheKey(reqA) == baseCacheKey(reqB) {
+		t.Errorf("requests to different hosts at the same path got the same cache key: %q", baseCacheKey(reqA))
+	}
+}
+
+func TestScopedInvalidatePatternConfinesToHost(t *testing.T) {
+	req := &http.Request{Host: "a.example.com"}
+	got := scopedInvalidatePattern(req, "*")
+	if got != "* a.example.com*" {
+		t.Errorf("scopedInvalidatePattern = %q, want %q", got, "* a.example.com*")
+	}
+}
+
+func TestCacheKeyWithVary(t *testing.T) {
+	header := http.Header{}
+	header.Set("Accept-Encoding", "gzip")
+	header.Set("Accept-Language", "en")
+
+	// Order of the Vary names shouldn't affect the resulting key.
+	keyA := cacheKeyWithVary("base", []string{"Accept-Encoding", "Accept-Language"}, header)
+	keyB := cacheKeyWithVary("base", []string{"Accept-Language", "Accept-Encoding"}, header)
+	if keyA != keyB {
+		t.Errorf("cacheKeyWithVary order-dependence: %q != %q", keyA, keyB)
+	}
+
+	if cacheKeyWithVary("base", nil, header) != "base" {
+		t.Errorf("cacheKeyWithVary with no vary names should return base unchanged")
+	}
+}
+
+func TestEncodeDecodeVaryHeaders(t *testing.T) {
+	names := []string{"Accept-Encoding", "Accept-Language"}
+	stored := encodeVaryHeaders(names)
+	got := decodeVaryHeaders(stored)
+	if len(got) != 2 || got[0] != names[0] || got[1] != names[1] {
+		t.Errorf("decodeVaryHeaders(encodeVaryHeaders(%v)) = %v", names, got)
+	}
+}
+
+func TestParseVaryHeader(t *testing.T) {
+	got := parseVaryHeader(" Accept-Encoding ,  Accept-Language")
+	want := []string{"Accept-Encoding", "Accept-Language"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("parseVaryHeader() = %v, want %v", got, want)
+	}
+	if got := parseVaryHeader(""); got != nil {
+		t.Errorf("parseVaryHeader(\"\") = %v, want nil", got)
+	}
+}
@@ -0,0 +1,109 @@
+package crossover_cache
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// freshness is the subset of RFC 7234 Cache-Control (plus Expires) this
+// package acts on.
+type freshness struct {
+	noStore              bool
+	noCache              bool
+	private              bool
+	maxAge               time.Duration
+	hasMaxAge            bool
+	sMaxAge              time.Duration
+	hasSMaxAge           bool
+	staleWhileRevalidate time.Duration
+	staleIfError         time.Duration
+}
+
+// parseCacheControl reads every Cache-Control directive off header.
+func parseCacheControl(header http.Header) freshness {
+	var f freshness
+	for _, line := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(line, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			name = strings.ToLower(strings.TrimSpace(name))
+			value = strings.Trim(strings.TrimSpace(value), `"`)
+
+			switch name {
+			case "no-store":
+				f.noStore = true
+			case "no-cache":
+				f.noCache = true
+			case "private":
+				f.private = true
+			case "max-age":
+				if seconds, err := strconv.Atoi(value); err == nil {
+					f.maxAge = time.Duration(seconds) * time.Second
+					f.hasMaxAge = true
+				}
+			case "s-maxage":
+				if seconds, err := strconv.Atoi(value); err == nil {
+					f.sMaxAge = time.Duration(seconds) * time.Second
+					f.hasSMaxAge = true
+				}
+			case "stale-while-revalidate":
+				if seconds, err := strconv.Atoi(value); err == nil {
+					f.staleWhileRevalidate = time.Duration(seconds) * time.Second
+				}
+			case "stale-if-error":
+				if seconds, err := strconv.Atoi(value); err == nil {
+					f.staleIfError = time.Duration(seconds) * time.Second
+				}
+			}
+		}
+	}
+	return f
+}
+
+// freshnessLifetime derives how long a response may be served without
+// revalidation: s-maxage (shared caches only) beats max-age, which beats
+// Expires, which falls back to defaultTTL.
+func freshnessLifetime(header http.Header, f freshness, defaultTTL time.Duration) time.Duration {
+	switch {
+	case f.hasSMaxAge:
+		return f.sMaxAge
+	case f.hasMaxAge:
+		return f.maxAge
+	}
+	if expires := header.Get("Expires"); expires != "" {
+		if t, err := http.ParseTime(expires); err == nil {
+			if d := time.Until(t); d > 0 {
+				return d
+			}
+			return 0
+		}
+	}
+	return defaultTTL
+}
+
+// isNonCacheable is a conservative check for origin responses that must
+// never be served from cache: server errors, an explicit Cache-Control
+// opt-out, Vary: * (which means no request-header projection can key it),
+// and Set-Cookie responses.
+func isNonCacheable(status int, headers http.Header, f freshness) bool {
+	if status >= http.StatusInternalServerError {
+		return true
+	}
+	if f.noStore || f.private {
+		return true
+	}
+	if headers.Get("Vary") == "*" {
+		return true
+	}
+	if len(headers.Values("Set-Cookie")) > 0 {
+		return true
+	}
+	return false
+}
+
+// isCacheableMethod reports whether responses to method may ever be stored;
+// non-idempotent methods are always proxied straight through.
+func isCacheableMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
@@ -0,0 +1,101 @@
+package crossover_cache
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseCacheControl(t *testing.T) {
+	cases := []struct {
+		name   string
+		header string
+		want   freshness
+	}{
+		{"no-store", "no-store", freshness{noStore: true}},
+		{"no-cache", "no-cache", freshness{noCache: true}},
+		{"private", "private", freshness{private: true}},
+		{"max-age", "max-age=30", freshness{maxAge: 30 * time.Second, hasMaxAge: true}},
+		{"s-maxage takes the shared-cache value", "max-age=30, s-maxage=60",
+			freshness{maxAge: 30 * time.Second, hasMaxAge: true, sMaxAge: 60 * time.Second, hasSMaxAge: true}},
+		{"stale-while-revalidate", "max-age=30, stale-while-revalidate=10",
+			freshness{maxAge: 30 * time.Second, hasMaxAge: true, staleWhileRevalidate: 10 * time.Second}},
+		{"unknown directive ignored", "max-age=30, weird-thing",
+			freshness{maxAge: 30 * time.Second, hasMaxAge: true}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			header := http.Header{}
+			header.Set("Cache-Control", tc.header)
+			got := parseCacheControl(header)
+			if got != tc.want {
+				t.Errorf("parseCacheControl(%q) = %+v, want %+v", tc.header, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFreshnessLifetimePrecedence(t *testing.T) {
+	header := http.Header{}
+	header.Set("Expires", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+
+	// s-maxage beats max-age beats Expires beats the default.
+	f := freshness{maxAge: 10 * time.Second, hasMaxAge: true, sMaxAge: 20 * time.Second, hasSMaxAge: true}
+	if got := freshnessLifetime(header, f, time.Minute); got != 20*time.Second {
+		t.Errorf("s-maxage precedence: got %s, want %s", got, 20*time.Second)
+	}
+
+	f = freshness{maxAge: 10 * time.Second, hasMaxAge: true}
+	if got := freshnessLifetime(header, f, time.Minute); got != 10*time.Second {
+		t.Errorf("max-age precedence: got %s, want %s", got, 10*time.Second)
+	}
+
+	f = freshness{}
+	if got := freshnessLifetime(header, f, time.Minute); got <= 50*time.Minute || got > time.Hour {
+		t.Errorf("Expires fallback: got %s, want close to 1h", got)
+	}
+
+	if got := freshnessLifetime(http.Header{}, f, time.Minute); got != time.Minute {
+		t.Errorf("default fallback: got %s, want %s", got, time.Minute)
+	}
+}
+
+func TestIsNonCacheable(t *testing.T) {
+	cases := []struct {
+		name    string
+		status  int
+		headers http.Header
+		f       freshness
+		want    bool
+	}{
+		{"ok", http.StatusOK, http.Header{}, freshness{}, false},
+		{"server error", http.StatusInternalServerError, http.Header{}, freshness{}, true},
+		{"no-store", http.StatusOK, http.Header{}, freshness{noStore: true}, true},
+		{"private", http.StatusOK, http.Header{}, freshness{private: true}, true},
+		{"vary star", http.StatusOK, http.Header{"Vary": {"*"}}, freshness{}, true},
+		{"set-cookie", http.StatusOK, http.Header{"Set-Cookie": {"a=b"}}, freshness{}, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isNonCacheable(tc.status, tc.headers, tc.f); got != tc.want {
+				t.Errorf("isNonCacheable() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsCacheableMethod(t *testing.T) {
+	cases := map[string]bool{
+		http.MethodGet:  true,
+		http.MethodHead: true,
+		http.MethodPost: false,
+		"PURGE":         false,
+	}
+	for method, want := range cases {
+		if got := isCacheableMethod(method); got != want {
+			t.Errorf("isCacheableMethod(%q) = %v, want %v", method, got, want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package crossover_cache
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/kotalco/resp"
+)
+
+// invalidate evicts pattern locally and publishes it on invalidateChannel
+// so every other Cache instance evicts it too.
+func (c *Cache) invalidate(ctx context.Context, pattern string) {
+	c.evictPattern(ctx, pattern)
+	if err := c.redisClient.Publish(ctx, c.invalidateChannel, pattern); err != nil {
+		log.Printf("failed to publish cache invalidation for %q: %s", pattern, err)
+	}
+}
+
+// evictPattern resolves pattern (a literal key or a glob, per the KEYS
+// command) to concrete keys and unlinks each of them.
+func (c *Cache) evictPattern(ctx context.Context, pattern string) {
+	keys, err := c.redisClient.Keys(ctx, pattern)
+	if err != nil {
+		log.Printf("failed to resolve cache invalidation pattern %q: %s", pattern, err)
+		return
+	}
+	for _, key := range keys {
+		if err := c.redisClient.Unlink(ctx, key); err != nil {
+			log.Printf("failed to evict cache key %q: %s", key, err)
+		}
+	}
+}
+
+// watchInvalidations subscribes to invalidateChannel for the lifetime of
+// ctx, evicting whatever pattern each message carries. It reconnects on
+// error so a transient Redis hiccup doesn't permanently desync this
+// instance from its peers, re-resolving the subscribe address on every
+// (re)connect so a Sentinel failover is picked up rather than leaving the
+// subscriber listening on a demoted master.
+func (c *Cache) watchInvalidations(ctx context.Context, cfg resp.Config) {
+	dialer := resp.NewDialer()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		address, err := subscriberAddress(ctx, dialer, cfg)
+		if err != nil {
+			log.Printf("cache invalidation: could not resolve subscribe address: %s", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		subscriber, err := resp.NewSubscriber(dialer, address, cfg.Username, cfg.Password)
+		if err != nil {
+			log.Printf("cache invalidation subscriber: %s", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		if err := subscriber.Subscribe(ctx, c.invalidateChannel); err != nil {
+			log.Printf("cache invalidation subscribe failed: %s", err)
+			_ = subscriber.Close()
+			time.Sleep(time.Second)
+			continue
+		}
+
+		for {
+			message, err := subscriber.Receive(ctx)
+			if err != nil {
+				log.Printf("cache invalidation receive failed: %s", err)
+				break
+			}
+			c.evictPattern(ctx, message.Payload)
+		}
+		_ = subscriber.Close()
+	}
+}
+
+// subscriberAddress resolves the node watchInvalidations should subscribe
+// on. For a Sentinel-managed deployment, cfg.Addresses are Sentinel
+// endpoints, not data nodes - Publish (via Client) sends to the discovered
+// master, so the subscriber must resolve and target that same master
+// rather than one of the Sentinels, or it would listen on a channel the
+// publishes never reach. For single-instance and Cluster deployments,
+// cfg.Addresses[0] is a data node directly; any Cluster node works, since
+// PUBLISH propagates cluster-wide.
+func subscriberAddress(ctx context.Context, dialer resp.IDialer, cfg resp.Config) (string, error) {
+	if cfg.SentinelMasterSet != "" {
+		return resp.DiscoverMaster(ctx, dialer, cfg)
+	}
+	if len(cfg.Addresses) == 0 {
+		return "", errors.New("no redis addresses configured")
+	}
+	return cfg.Addresses[0], nil
+}
@@ -1,29 +1,83 @@
 package crossover_cache
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
-	"encoding/gob"
 	"github.com/kotalco/resp"
 	"log"
 	"net/http"
+	"time"
 )
 
 const (
 	DefaultCacheExpiry = 15
+	// DefaultInvalidateChannel is the pub/sub channel Cache instances use
+	// to coordinate invalidation when none is configured.
+	DefaultInvalidateChannel = "crossover_cache:invalidate"
+	// DefaultMaxCacheableBodyBytes bounds how much of a response body is
+	// buffered for caching when Config.MaxCacheableBodyBytes isn't set.
+	// Larger bodies still stream straight to the client - they're just
+	// never stored.
+	DefaultMaxCacheableBodyBytes = 2 << 20 // 2MiB
+	// InvalidateHeader, set on a request, purges the entries on the
+	// request's own host whose URL matches its value (a literal path or a
+	// glob pattern, see scopedInvalidatePattern). The request itself is then
+	// served through the cache as usual.
+	InvalidateHeader = "X-Cache-Invalidate"
+	// MethodPurge, like InvalidateHeader, purges entries: those of the
+	// request's own URL, for every method and Vary variant. Any cached URL on
+	// the same host that begins with the request's URL is purged too.
+	MethodPurge = "PURGE"
 )
 
 type CachedResponse struct {
 	StatusCode int
 	Headers    map[string][]string
 	Body       []byte
+	// StoredAt and ExpiresAt bound this entry's freshness lifetime, derived
+	// from the origin's Cache-Control/Expires headers at store time.
+	StoredAt  time.Time
+	ExpiresAt time.Time
+	// StaleWhileRevalidate and StaleIfError extend how long this entry may
+	// keep being served past ExpiresAt: the former while a revalidation is
+	// attempted in the background, the latter only if a synchronous
+	// revalidation fails with a server error.
+	StaleWhileRevalidate time.Duration
+	StaleIfError         time.Duration
 }
 
 type Config struct {
+	// RedisAddress is a single Redis instance, e.g. "redis:6379". Ignored
+	// when RedisAddresses is set.
 	RedisAddress string
-	RedisAuth    string
-	CacheExpiry  int //in seconds
+	// RedisAddresses, when it holds more than one entry, is treated as a
+	// Redis Cluster; combined with SentinelMasterSet, it's treated as the
+	// set of Sentinel endpoints to discover the master through.
+	RedisAddresses []string
+	// RedisUsername authenticates via Redis 6+ ACL AUTH. Leave empty to
+	// authenticate with RedisAuth alone, the legacy password-only form.
+	RedisUsername     string
+	RedisAuth         string
+	SentinelMasterSet string
+	// SentinelPassword authenticates against the Sentinel instances
+	// themselves, as opposed to the Redis master/replicas they track.
+	// Leave empty if the Sentinels require no auth of their own.
+	SentinelPassword string
+	// RedisPoolSize bounds the number of pooled connections kept per node.
+	RedisPoolSize int
+	CacheExpiry   int //in seconds
+	// InvalidateChannel is the pub/sub channel instances publish and
+	// subscribe to for cross-instance cache invalidation. Defaults to
+	// DefaultInvalidateChannel.
+	InvalidateChannel string
+	// MaxCacheableBodyBytes caps how much of a response body is buffered
+	// for caching; bodies larger than this still stream to the client but
+	// are never stored. Defaults to DefaultMaxCacheableBodyBytes.
+	MaxCacheableBodyBytes int
+	// Compression selects how stored bodies are compressed before being
+	// written to Redis: "" or "none" (default) stores them as-is, "gzip"
+	// compresses with compress/gzip. "zstd" is recognized but not yet
+	// implemented.
+	Compression string
 }
 
 // CreateConfig creates the default plugin configuration.
@@ -32,106 +86,311 @@ func CreateConfig() *Config {
 }
 
 type Cache struct {
-	next         http.Handler
-	name         string
-	redisAuth    string
-	redisAddress string
-	cacheExpiry  int
+	next                  http.Handler
+	name                  string
+	redisClient           resp.IClient
+	cacheExpiry           int
+	invalidateChannel     string
+	maxCacheableBodyBytes int64
+	compression           contentEncoding
 }
 
 func New(ctx context.Context, next http.Handler, config *Config, name string) (http.Handler, error) {
 	if config.CacheExpiry == 0 {
 		config.CacheExpiry = DefaultCacheExpiry
 	}
-	gob.Register(CachedResponse{})
+	if config.InvalidateChannel == "" {
+		config.InvalidateChannel = DefaultInvalidateChannel
+	}
+	if config.MaxCacheableBodyBytes == 0 {
+		config.MaxCacheableBodyBytes = DefaultMaxCacheableBodyBytes
+	}
+	compression, err := parseCompression(config.Compression)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := config.RedisAddresses
+	if len(addresses) == 0 && config.RedisAddress != "" {
+		addresses = []string{config.RedisAddress}
+	}
+
+	redisCfg := resp.Config{
+		Addresses:         addresses,
+		SentinelMasterSet: config.SentinelMasterSet,
+		SentinelPassword:  config.SentinelPassword,
+		Username:          config.RedisUsername,
+		Password:          config.RedisAuth,
+		PoolSize:          config.RedisPoolSize,
+	}
+	redisClient, err := resp.NewRedisClient(redisCfg)
+	if err != nil {
+		return nil, err
+	}
 
 	handler := &Cache{
-		next:         next,
-		name:         name,
-		redisAddress: config.RedisAddress,
-		redisAuth:    config.RedisAuth,
-		cacheExpiry:  config.CacheExpiry,
+		next:                  next,
+		name:                  name,
+		redisClient:           redisClient,
+		cacheExpiry:           config.CacheExpiry,
+		invalidateChannel:     config.InvalidateChannel,
+		maxCacheableBodyBytes: int64(config.MaxCacheableBodyBytes),
+		compression:           compression,
+	}
+	if len(addresses) > 0 {
+		go handler.watchInvalidations(ctx, redisCfg)
 	}
 	return handler, nil
 }
 
 func (c *Cache) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
-	respClient, err := resp.NewRedisClient(c.redisAddress, c.redisAuth)
-	if err != nil {
-		rw.WriteHeader(http.StatusInternalServerError)
-		log.Printf("Failed to create Redis Connection %s", err.Error())
-		rw.Write([]byte("something went wrong"))
+	base := baseCacheKey(req)
+
+	if pattern := req.Header.Get(InvalidateHeader); pattern != "" {
+		c.invalidate(req.Context(), scopedInvalidatePattern(req, pattern))
+	}
+	if req.Method == MethodPurge {
+		c.invalidate(req.Context(), scopedInvalidatePattern(req, req.URL.String()+"*"))
+		rw.WriteHeader(http.StatusOK)
 		return
 	}
-	defer respClient.Close()
-	// cache key based on the request
-	cacheKey := req.URL.Path
-
-	// retrieve the cached response
-	cachedData, err := respClient.Get(req.Context(), cacheKey)
-	if err == nil && cachedData != "" {
-		log.Println("cache hit")
-		// Cache hit - decode the base64 string
-		data, err := base64.StdEncoding.DecodeString(cachedData)
-		if err != nil {
-			log.Printf("Failed to decode base64 string: %s", err)
-			return
-		}
 
-		// Parse the cached response and write it to the original ResponseWriter
-		var cachedResponse CachedResponse
-		buffer := bytes.NewBuffer(data) // Use the decoded byte slice
-		dec := gob.NewDecoder(buffer)
-		if err := dec.Decode(&cachedResponse); err == nil {
-			for key, values := range cachedResponse.Headers {
-				for _, value := range values {
-					rw.Header().Add(key, value)
-				}
+	if !isCacheableMethod(req.Method) {
+		c.next.ServeHTTP(rw, req)
+		return
+	}
+
+	reqCacheControl := parseCacheControl(req.Header)
+	if reqCacheControl.noStore {
+		c.next.ServeHTTP(rw, req)
+		return
+	}
+
+	cacheKey := base
+	if varyNames, ok := c.lookupVary(req.Context(), base); ok {
+		cacheKey = cacheKeyWithVary(base, varyNames, req.Header)
+	}
+
+	if !reqCacheControl.noCache {
+		if cached, ok := c.loadCachedResponse(req.Context(), cacheKey); ok {
+			now := time.Now()
+			if now.Before(cached.ExpiresAt) {
+				log.Println("cache hit")
+				c.writeCachedResponse(rw, cached)
+				return
+			}
+
+			if cached.StaleWhileRevalidate > 0 && now.Before(cached.ExpiresAt.Add(cached.StaleWhileRevalidate)) {
+				log.Println("serving stale response, revalidating in the background")
+				c.writeCachedResponse(rw, cached)
+				go c.revalidateInBackground(req, base, cacheKey, cached)
+				return
+			}
+
+			log.Println("cached response expired, revalidating")
+			recorder := newResponseRecorder(c.maxCacheableBodyBytes)
+			fresh, notModified := c.revalidate(req, cached, recorder)
+			if notModified {
+				c.storeCachedResponse(req.Context(), cacheKey, fresh)
+				c.writeCachedResponse(rw, fresh)
+				return
+			}
+			if cached.StaleIfError > 0 && recorder.status >= http.StatusInternalServerError &&
+				now.Before(cached.ExpiresAt.Add(cached.StaleIfError)) {
+				log.Println("origin error on revalidation, serving stale response")
+				c.writeCachedResponse(rw, cached)
+				return
+			}
+			if recorder.Truncated() {
+				// The conditional round trip's body overran the cache limit,
+				// so only a partial copy was buffered - too incomplete to
+				// serve. Re-fetch straight through to the client instead.
+				log.Println("revalidation response exceeded cache size limit, re-fetching for client")
+				recorder = newStreamingRecorder(rw, c.maxCacheableBodyBytes)
+				c.next.ServeHTTP(recorder, req)
+				c.finishMiss(rw, req, base, recorder)
+				return
 			}
-			log.Println("writing response from cache")
-			rw.WriteHeader(cachedResponse.StatusCode)
-			_, _ = rw.Write(cachedResponse.Body)
+			// The conditional request already carries a fresh representation -
+			// handle it exactly like an ordinary cache miss, without fetching
+			// the origin a second time.
+			c.finishMiss(rw, req, base, recorder)
 			return
-		} else {
-			log.Printf("Failed to deserialize response from cache: %s", err.Error())
-			_ = respClient.Delete(req.Context(), cacheKey)
 		}
 	}
 
-	log.Println("cache hit")
-	// Cache miss - record the response
-	recorder := &responseRecorder{rw: rw}
+	log.Println("cache miss")
+	recorder := newStreamingRecorder(rw, c.maxCacheableBodyBytes)
 	c.next.ServeHTTP(recorder, req)
+	c.finishMiss(rw, req, base, recorder)
+}
+
+// finishMiss decides whether recorder's response may be cached and, when it
+// can be, stores it under the appropriate vary-aware key. If recorder
+// wasn't streaming, its response hasn't reached the client yet and this
+// also writes it to rw; a streaming recorder already forwarded every byte
+// as it arrived.
+func (c *Cache) finishMiss(rw http.ResponseWriter, req *http.Request, base string, recorder *responseRecorder) {
+	headers := recorder.Header().Clone()
+	f := parseCacheControl(headers)
+
+	cachedResponse := buildCachedResponse(recorder, headers, f, time.Duration(c.cacheExpiry)*time.Second)
 
-	// Serialize the response data
-	cachedResponse := CachedResponse{
-		StatusCode: recorder.status,
-		Headers:    recorder.Header().Clone(), // Convert http.Header to a map for serialization
-		Body:       recorder.body.Bytes(),
+	cacheKey := base
+	if varyNames := parseVaryHeader(headers.Get("Vary")); len(varyNames) > 0 {
+		c.storeVary(req.Context(), base, varyNames, cacheTTLSeconds(cachedResponse))
+		cacheKey = cacheKeyWithVary(base, varyNames, req.Header)
 	}
-	var buffer bytes.Buffer
-	enc := gob.NewEncoder(&buffer)
-	if err := enc.Encode(cachedResponse); err != nil {
-		log.Printf("Failed to serialize response for caching: %s", err)
-	} else {
-		log.Println("caching response to redis")
-		// Encode the buffer to a base64 string
-		encodedString := base64.StdEncoding.EncodeToString(buffer.Bytes())
-		// Store the serialized response in Redis
-		if err := respClient.SetWithTTL(req.Context(), cacheKey, encodedString, c.cacheExpiry); err != nil {
-			log.Printf("Failed to cache response in Redis: %s", err.Error())
+
+	switch {
+	case !isCacheableMethod(req.Method):
+		if recorder.status < http.StatusBadRequest {
+			// An unsafe method succeeded: the resource it targeted may have
+			// changed, so purge every cached representation of it.
+			c.invalidate(req.Context(), scopedInvalidatePattern(req, req.URL.String()+"*"))
 		}
+	case recorder.Truncated():
+		log.Printf("response body for %q exceeded the %d byte cache limit, not caching", cacheKey, c.maxCacheableBodyBytes)
+		c.invalidate(req.Context(), cacheKey)
+	case isNonCacheable(recorder.status, headers, f):
+		// The origin says this response shouldn't be (re)used - drop any
+		// stale copy, here and on every other instance.
+		c.invalidate(req.Context(), cacheKey)
+	default:
+		log.Println("caching response to redis")
+		c.storeCachedResponse(req.Context(), cacheKey, cachedResponse)
+	}
+
+	if !recorder.streaming {
+		c.writeCachedResponse(rw, cachedResponse)
 	}
+}
+
+// revalidateInBackground refreshes a stale-while-revalidate entry without
+// making the client that was served the stale copy wait for it. It runs
+// detached from req's context, which is liable to be canceled the moment
+// ServeHTTP returns.
+func (c *Cache) revalidateInBackground(req *http.Request, base string, cacheKey string, cached CachedResponse) {
+	bgReq := req.Clone(context.Background())
+	recorder := newResponseRecorder(c.maxCacheableBodyBytes)
+	fresh, notModified := c.revalidate(bgReq, cached, recorder)
+	if notModified {
+		c.storeCachedResponse(context.Background(), cacheKey, fresh)
+		return
+	}
+	if recorder.status >= http.StatusInternalServerError {
+		log.Printf("background revalidation of %q failed with status %d, keeping stale entry", cacheKey, recorder.status)
+		return
+	}
+	if recorder.Truncated() {
+		log.Printf("background revalidation of %q exceeded the cache size limit, not caching", cacheKey)
+		return
+	}
+
+	headers := recorder.Header().Clone()
+	f := parseCacheControl(headers)
+	fresh = buildCachedResponse(recorder, headers, f, time.Duration(c.cacheExpiry)*time.Second)
+	if varyNames := parseVaryHeader(headers.Get("Vary")); len(varyNames) > 0 {
+		c.storeVary(context.Background(), base, varyNames, cacheTTLSeconds(fresh))
+	}
+	if isNonCacheable(recorder.status, headers, f) {
+		c.invalidate(context.Background(), cacheKey)
+		return
+	}
+	c.storeCachedResponse(context.Background(), cacheKey, fresh)
+}
+
+// buildCachedResponse captures recorder's status/body alongside the
+// freshness metadata derived from headers/f.
+func buildCachedResponse(recorder *responseRecorder, headers http.Header, f freshness, defaultTTL time.Duration) CachedResponse {
+	now := time.Now()
+	return CachedResponse{
+		StatusCode:           recorder.status,
+		Headers:              headers,
+		Body:                 recorder.body.Bytes(),
+		StoredAt:             now,
+		ExpiresAt:            now.Add(freshnessLifetime(headers, f, defaultTTL)),
+		StaleWhileRevalidate: f.staleWhileRevalidate,
+		StaleIfError:         f.staleIfError,
+	}
+}
 
-	// Write the response to the client
-	for key, values := range cachedResponse.Headers {
+// writeCachedResponse replays a stored or freshly built response to rw.
+func (c *Cache) writeCachedResponse(rw http.ResponseWriter, cached CachedResponse) {
+	for key, values := range cached.Headers {
 		for _, value := range values {
 			rw.Header().Add(key, value)
 		}
 	}
-	log.Println("writing response")
-	rw.WriteHeader(cachedResponse.StatusCode)
-	_, err = rw.Write(cachedResponse.Body)
-	return
+	rw.WriteHeader(cached.StatusCode)
+	_, _ = rw.Write(cached.Body)
+}
 
+// loadCachedResponse reads and decodes the entry stored under cacheKey, if
+// any. A decode failure evicts the entry rather than serving it.
+func (c *Cache) loadCachedResponse(ctx context.Context, cacheKey string) (CachedResponse, bool) {
+	stored, err := c.redisClient.Get(ctx, cacheKey)
+	if err != nil || stored == "" {
+		return CachedResponse{}, false
+	}
+
+	cached, err := decodeCachedResponse([]byte(stored))
+	if err != nil {
+		log.Printf("Failed to deserialize response from cache: %s", err)
+		_ = c.redisClient.Delete(ctx, cacheKey)
+		return CachedResponse{}, false
+	}
+	return cached, true
+}
+
+// cacheTTLSeconds is how long cached should live in Redis: long enough to
+// cover its freshness lifetime plus whichever of
+// stale-while-revalidate/stale-if-error reaches furthest past it - past that
+// point nothing would ever read the entry again.
+func cacheTTLSeconds(cached CachedResponse) int {
+	stale := cached.StaleWhileRevalidate
+	if cached.StaleIfError > stale {
+		stale = cached.StaleIfError
+	}
+	ttl := int(time.Until(cached.ExpiresAt).Seconds()) + int(stale.Seconds())
+	if ttl < 1 {
+		ttl = 1
+	}
+	return ttl
+}
+
+// storeCachedResponse encodes cached in this middleware's binary framing
+// (see storage.go), optionally compressed per c.compression, and stores it
+// under cacheKey with a Redis TTL covering its freshness lifetime.
+func (c *Cache) storeCachedResponse(ctx context.Context, cacheKey string, cached CachedResponse) {
+	encoded, err := encodeCachedResponse(cached, c.compression)
+	if err != nil {
+		log.Printf("Failed to serialize response for caching: %s", err)
+		return
+	}
+
+	if err := c.redisClient.SetWithTTL(ctx, cacheKey, string(encoded), cacheTTLSeconds(cached)); err != nil {
+		log.Printf("Failed to cache response in Redis: %s", err.Error())
+	}
+}
+
+// lookupVary returns the Vary header names registered for base, if the
+// origin has ever sent one for this request.
+func (c *Cache) lookupVary(ctx context.Context, base string) ([]string, bool) {
+	stored, err := c.redisClient.Get(ctx, varyRegistryKey(base))
+	if err != nil || stored == "" {
+		return nil, false
+	}
+	return decodeVaryHeaders(stored), true
+}
+
+// storeVary registers the Vary header names an origin response carried for
+// base, so later requests can compute their cache key without a round trip.
+// ttlSeconds should track the lifetime of the entry this Vary applies to -
+// not c.cacheExpiry - or the registry entry can expire while the entry it
+// describes is still fresh, silently falling back to the bare (wrong) key.
+func (c *Cache) storeVary(ctx context.Context, base string, names []string, ttlSeconds int) {
+	if err := c.redisClient.SetWithTTL(ctx, varyRegistryKey(base), encodeVaryHeaders(names), ttlSeconds); err != nil {
+		log.Printf("failed to store vary registry for %q: %s", base, err)
+	}
 }
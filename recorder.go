@@ -5,22 +5,79 @@ import (
 	"net/http"
 )
 
+// responseRecorder captures next's response for possible caching. In
+// buffering mode (streaming == false, used to probe the origin during
+// conditional revalidation) it only buffers, since there's no real client
+// waiting on the result. In streaming mode (the ordinary cache-miss path)
+// it forwards every byte to rw as it arrives, so an origin that takes a
+// while doesn't make the client wait for the whole response before seeing
+// any of it, while also teeing up to maxBody bytes into body for possible
+// caching. Once the body exceeds maxBody, buffering stops - Truncated()
+// reports true - but bytes already in flight keep streaming to rw
+// untouched; the response is just never cached.
 type responseRecorder struct {
-	rw     http.ResponseWriter
-	status int
-	body   bytes.Buffer
-	header http.Header
+	rw        http.ResponseWriter
+	streaming bool
+	maxBody   int64
+
+	status        int
+	header        http.Header
+	body          bytes.Buffer
+	truncated     bool
+	headerWritten bool
 }
 
-func (r *responseRecorder) Header() http.Header {
-	return r.header
+// newResponseRecorder returns a recorder that only buffers next's response,
+// up to maxBody bytes, never forwarding it anywhere.
+func newResponseRecorder(maxBody int64) *responseRecorder {
+	return &responseRecorder{header: make(http.Header), maxBody: maxBody}
 }
 
-func (r *responseRecorder) Write(b []byte) (int, error) {
-	return r.body.Write(b) // Just buffer the body, don't write to rw
+// newStreamingRecorder returns a recorder that forwards next's response to
+// rw as it's written, while buffering up to maxBody bytes of the body for
+// possible caching.
+func newStreamingRecorder(rw http.ResponseWriter, maxBody int64) *responseRecorder {
+	return &responseRecorder{rw: rw, streaming: true, maxBody: maxBody, header: make(http.Header)}
+}
 
+func (r *responseRecorder) Header() http.Header {
+	return r.header
 }
 
 func (r *responseRecorder) WriteHeader(statusCode int) {
+	if r.headerWritten {
+		return
+	}
 	r.status = statusCode
+	r.headerWritten = true
+	if r.streaming {
+		dst := r.rw.Header()
+		for key, values := range r.header {
+			dst[key] = values
+		}
+		r.rw.WriteHeader(statusCode)
+	}
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if !r.headerWritten {
+		r.WriteHeader(http.StatusOK)
+	}
+	if !r.truncated {
+		if r.maxBody > 0 && int64(r.body.Len()+len(b)) > r.maxBody {
+			r.truncated = true
+		} else {
+			r.body.Write(b)
+		}
+	}
+	if r.streaming {
+		return r.rw.Write(b)
+	}
+	return len(b), nil
+}
+
+// Truncated reports whether the body exceeded maxBody, meaning body only
+// holds a partial copy that must never be stored.
+func (r *responseRecorder) Truncated() bool {
+	return r.truncated
 }
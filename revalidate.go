@@ -0,0 +1,34 @@
+package crossover_cache
+
+import (
+	"net/http"
+	"time"
+)
+
+// revalidate issues a conditional request to the origin using cached's
+// validators (ETag/Last-Modified), recording the origin's response into
+// recorder. A 304 means cached is still good: its freshness window is
+// refreshed and its body is reused without re-transferring it from the
+// origin; the caller stores the refreshed entry. Any other status is a fresh representation, now sitting in recorder,
+// that the caller should treat like an ordinary cache miss.
+func (c *Cache) revalidate(req *http.Request, cached CachedResponse, recorder *responseRecorder) (fresh CachedResponse, notModified bool) {
+	condReq := req.Clone(req.Context())
+	if etag := http.Header(cached.Headers).Get("Etag"); etag != "" {
+		condReq.Header.Set("If-None-Match", etag)
+	}
+	if lastModified := http.Header(cached.Headers).Get("Last-Modified"); lastModified != "" {
+		condReq.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	c.next.ServeHTTP(recorder, condReq)
+
+	if recorder.status == http.StatusNotModified {
+		cached.StoredAt = time.Now()
+		f := parseCacheControl(recorder.Header())
+		cached.ExpiresAt = cached.StoredAt.Add(freshnessLifetime(recorder.Header(), f, time.Duration(c.cacheExpiry)*time.Second))
+		cached.StaleWhileRevalidate = f.staleWhileRevalidate
+		cached.StaleIfError = f.staleIfError
+		return cached, true
+	}
+	return CachedResponse{}, false
+}
@@ -0,0 +1,224 @@
+package crossover_cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// storageFormatVersion guards against decoding an entry written by an
+// incompatible version of this package.
+const storageFormatVersion = 1
+
+// contentEncoding records which compression, if any, was applied to a
+// stored body. It travels with the entry itself rather than being implied
+// by the instance's current Config.Compression, so changing that config
+// doesn't strand entries an earlier instance wrote under a different one.
+type contentEncoding byte
+
+const (
+	encodingNone contentEncoding = iota
+	encodingGzip
+)
+
+// parseCompression validates Config.Compression and maps it to the
+// contentEncoding every entry this instance stores is tagged with.
+func parseCompression(name string) (contentEncoding, error) {
+	switch strings.ToLower(name) {
+	case "", "none":
+		return encodingNone, nil
+	case "gzip":
+		return encodingGzip, nil
+	case "zstd":
+		return 0, errors.New("crossover_cache: Compression \"zstd\" is not implemented yet; use \"gzip\" or leave Compression unset")
+	default:
+		return 0, fmt.Errorf("crossover_cache: unknown Compression %q", name)
+	}
+}
+
+// encodeCachedResponse serializes cached as a fixed-size metadata block
+// followed by the header map and then the body, optionally compressed with
+// encoding. Every variable-length field is length-prefixed, unlike gob, so
+// decoding never has to guess at field boundaries.
+func encodeCachedResponse(cached CachedResponse, encoding contentEncoding) ([]byte, error) {
+	body := cached.Body
+	if encoding == encodingGzip {
+		var compressed bytes.Buffer
+		gz := gzip.NewWriter(&compressed)
+		if _, err := gz.Write(cached.Body); err != nil {
+			return nil, fmt.Errorf("compress cached body: %w", err)
+		}
+		if err := gz.Close(); err != nil {
+			return nil, fmt.Errorf("compress cached body: %w", err)
+		}
+		body = compressed.Bytes()
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(storageFormatVersion)
+	buf.WriteByte(byte(encoding))
+	writeInt64(&buf, int64(cached.StatusCode))
+	writeInt64(&buf, cached.StoredAt.UnixNano())
+	writeInt64(&buf, cached.ExpiresAt.UnixNano())
+	writeInt64(&buf, int64(cached.StaleWhileRevalidate))
+	writeInt64(&buf, int64(cached.StaleIfError))
+
+	writeUint32(&buf, uint32(len(cached.Headers)))
+	for key, values := range cached.Headers {
+		writeString(&buf, key)
+		writeUint32(&buf, uint32(len(values)))
+		for _, value := range values {
+			writeString(&buf, value)
+		}
+	}
+
+	writeUint32(&buf, uint32(len(body)))
+	buf.Write(body)
+
+	return buf.Bytes(), nil
+}
+
+// decodeCachedResponse reverses encodeCachedResponse, transparently
+// decompressing the body according to the encoding byte the entry itself
+// carries.
+func decodeCachedResponse(data []byte) (CachedResponse, error) {
+	r := bytes.NewReader(data)
+
+	version, err := r.ReadByte()
+	if err != nil {
+		return CachedResponse{}, fmt.Errorf("read cache entry version: %w", err)
+	}
+	if version != storageFormatVersion {
+		return CachedResponse{}, fmt.Errorf("unsupported cache entry version %d", version)
+	}
+
+	encodingByte, err := r.ReadByte()
+	if err != nil {
+		return CachedResponse{}, fmt.Errorf("read cache entry encoding: %w", err)
+	}
+
+	statusCode, err := readInt64(r)
+	if err != nil {
+		return CachedResponse{}, fmt.Errorf("read cache entry status: %w", err)
+	}
+	storedAt, err := readInt64(r)
+	if err != nil {
+		return CachedResponse{}, fmt.Errorf("read cache entry stored-at: %w", err)
+	}
+	expiresAt, err := readInt64(r)
+	if err != nil {
+		return CachedResponse{}, fmt.Errorf("read cache entry expires-at: %w", err)
+	}
+	staleWhileRevalidate, err := readInt64(r)
+	if err != nil {
+		return CachedResponse{}, fmt.Errorf("read cache entry stale-while-revalidate: %w", err)
+	}
+	staleIfError, err := readInt64(r)
+	if err != nil {
+		return CachedResponse{}, fmt.Errorf("read cache entry stale-if-error: %w", err)
+	}
+
+	headerCount, err := readUint32(r)
+	if err != nil {
+		return CachedResponse{}, fmt.Errorf("read cache entry header count: %w", err)
+	}
+	headers := make(map[string][]string, headerCount)
+	for i := uint32(0); i < headerCount; i++ {
+		key, err := readString(r)
+		if err != nil {
+			return CachedResponse{}, fmt.Errorf("read cache entry header name: %w", err)
+		}
+		valueCount, err := readUint32(r)
+		if err != nil {
+			return CachedResponse{}, fmt.Errorf("read cache entry header value count: %w", err)
+		}
+		values := make([]string, valueCount)
+		for j := range values {
+			values[j], err = readString(r)
+			if err != nil {
+				return CachedResponse{}, fmt.Errorf("read cache entry header value: %w", err)
+			}
+		}
+		headers[key] = values
+	}
+
+	bodyLen, err := readUint32(r)
+	if err != nil {
+		return CachedResponse{}, fmt.Errorf("read cache entry body length: %w", err)
+	}
+	body := make([]byte, bodyLen)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return CachedResponse{}, fmt.Errorf("read cache entry body: %w", err)
+	}
+
+	if contentEncoding(encodingByte) == encodingGzip {
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return CachedResponse{}, fmt.Errorf("decompress cache entry body: %w", err)
+		}
+		body, err = io.ReadAll(gz)
+		if err != nil {
+			return CachedResponse{}, fmt.Errorf("decompress cache entry body: %w", err)
+		}
+	}
+
+	return CachedResponse{
+		StatusCode:           int(statusCode),
+		Headers:              headers,
+		Body:                 body,
+		StoredAt:             time.Unix(0, storedAt),
+		ExpiresAt:            time.Unix(0, expiresAt),
+		StaleWhileRevalidate: time.Duration(staleWhileRevalidate),
+		StaleIfError:         time.Duration(staleIfError),
+	}, nil
+}
+
+func writeUint32(buf *bytes.Buffer, v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	buf.Write(b[:])
+}
+
+func writeInt64(buf *bytes.Buffer, v int64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(v))
+	buf.Write(b[:])
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	writeUint32(buf, uint32(len(s)))
+	buf.WriteString(s)
+}
+
+func readUint32(r *bytes.Reader) (uint32, error) {
+	var b [4]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b[:]), nil
+}
+
+func readInt64(r *bytes.Reader) (int64, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(b[:])), nil
+}
+
+func readString(r *bytes.Reader) (string, error) {
+	n, err := readUint32(r)
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
@@ -0,0 +1,117 @@
+package crossover_cache
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestEncodeDecodeCachedResponseRoundTrip(t *testing.T) {
+	cases := []struct {
+		name     string
+		encoding contentEncoding
+	}{
+		{"uncompressed", encodingNone},
+		{"gzip", encodingGzip},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			now := time.Unix(1700000000, 0).UTC()
+			want := CachedResponse{
+				StatusCode: 200,
+				Headers: map[string][]string{
+					"Content-Type": {"text/plain"},
+					"Set-Cookie":   {"a=b", "c=d"},
+				},
+				Body:                 []byte("hello, world - this is the cached body"),
+				StoredAt:             now,
+				ExpiresAt:            now.Add(time.Minute),
+				StaleWhileRevalidate: 30 * time.Second,
+				StaleIfError:         time.Hour,
+			}
+
+			encoded, err := encodeCachedResponse(want, tc.encoding)
+			if err != nil {
+				t.Fatalf("encodeCachedResponse: %v", err)
+			}
+
+			got, err := decodeCachedResponse(encoded)
+			if err != nil {
+				t.Fatalf("decodeCachedResponse: %v", err)
+			}
+
+			if got.StatusCode != want.StatusCode {
+				t.Errorf("StatusCode = %d, want %d", got.StatusCode, want.StatusCode)
+			}
+			if !bytes.Equal(got.Body, want.Body) {
+				t.Errorf("Body = %q, want %q", got.Body, want.Body)
+			}
+			if !got.StoredAt.Equal(want.StoredAt) {
+				t.Errorf("StoredAt = %v, want %v", got.StoredAt, want.StoredAt)
+			}
+			if !got.ExpiresAt.Equal(want.ExpiresAt) {
+				t.Errorf("ExpiresAt = %v, want %v", got.ExpiresAt, want.ExpiresAt)
+			}
+			if got.StaleWhileRevalidate != want.StaleWhileRevalidate {
+				t.Errorf("StaleWhileRevalidate = %v, want %v", got.StaleWhileRevalidate, want.StaleWhileRevalidate)
+			}
+			if got.StaleIfError != want.StaleIfError {
+				t.Errorf("StaleIfError = %v, want %v", got.StaleIfError, want.StaleIfError)
+			}
+			for key, values := range want.Headers {
+				gotValues := got.Headers[key]
+				if len(gotValues) != len(values) {
+					t.Errorf("Headers[%q] = %v, want %v", key, gotValues, values)
+					continue
+				}
+				for i := range values {
+					if gotValues[i] != values[i] {
+						t.Errorf("Headers[%q][%d] = %q, want %q", key, i, gotValues[i], values[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeCachedResponseRejectsBadVersion(t *testing.T) {
+	encoded, err := encodeCachedResponse(CachedResponse{}, encodingNone)
+	if err != nil {
+		t.Fatalf("encodeCachedResponse: %v", err)
+	}
+	encoded[0] = storageFormatVersion + 1
+
+	if _, err := decodeCachedResponse(encoded); err == nil {
+		t.Fatal("decodeCachedResponse with an unsupported version succeeded, want error")
+	}
+}
+
+func TestParseCompression(t *testing.T) {
+	cases := []struct {
+		name    string
+		want    contentEncoding
+		wantErr bool
+	}{
+		{"", encodingNone, false},
+		{"none", encodingNone, false},
+		{"gzip", encodingGzip, false},
+		{"zstd", 0, true},
+		{"bogus", 0, true},
+	}
+	for _, tc := range cases {
+		got, err := parseCompression(tc.name)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("parseCompression(%q) succeeded, want error", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseCompression(%q): %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("parseCompression(%q) = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
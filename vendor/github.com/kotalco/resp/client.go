@@ -16,169 +16,547 @@ const (
 	SetWithTTLCmd = "*5\r\n$3\r\nSET\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n$2\r\nEX\r\n$%d\r\n%d\r\n"
 	GetCmd        = "*2\r\n$3\r\nGET\r\n$%d\r\n%s\r\n"
 	DeleteCmd     = "*2\r\n$3\r\nDEL\r\n$%d\r\n%s\r\n"
+	UnlinkCmd     = "*2\r\n$6\r\nUNLINK\r\n$%d\r\n%s\r\n"
+)
+
+// topology identifies how Client routes commands to nodes.
+type topology int
+
+const (
+	topologySingle topology = iota
+	topologySentinel
+	topologyCluster
 )
 
 type IClient interface {
-	Do(ctx context.Context, command string) (string, error)
+	// Do sends a raw RESP command to the node responsible for key and
+	// returns the parsed reply. key is only used for routing in cluster
+	// mode; pass "" for commands (like PING) that aren't tied to a key.
+	Do(ctx context.Context, key string, command string) (*Reply, error)
+	// Pipeline acquires a connection and returns a batch of commands to run
+	// against it with a single round trip. key is used for routing as in
+	// Do.
+	Pipeline(ctx context.Context, key string) (*Pipeline, error)
+	// Transaction runs commands atomically via MULTI/EXEC and returns each
+	// command's reply in order.
+	Transaction(ctx context.Context, key string, commands ...string) ([]*Reply, error)
 	Ping(ctx context.Context) (string, error)
 	Set(ctx context.Context, key string, value string) error
 	SetWithTTL(ctx context.Context, key string, value string, ttl int) error
 	Get(ctx context.Context, key string) (string, error)
+	MGet(ctx context.Context, keys ...string) ([]string, error)
+	MSet(ctx context.Context, values map[string]string) error
 	Delete(ctx context.Context, key string) error
+	// Unlink behaves like Delete but reclaims memory asynchronously on the
+	// server, making it the preferred way to evict keys off the hot path.
+	Unlink(ctx context.Context, key string) error
+	// Keys returns every stored key matching a glob pattern, as used to
+	// resolve invalidation patterns (e.g. "articles:*") to concrete keys.
+	Keys(ctx context.Context, pattern string) ([]string, error)
+	// Publish broadcasts message on channel, e.g. to announce a cache
+	// invalidation to every other Cache instance.
+	Publish(ctx context.Context, channel string, message string) error
 	Incr(ctx context.Context, key string) (int, error)
 	Expire(ctx context.Context, key string, seconds int) (bool, error)
 	Close() error
 }
 
+// Client is a pooled Redis client. Depending on Config it talks to a single
+// instance, a Sentinel-managed master, or a Redis Cluster; ServeHTTP-style
+// callers dial once via NewRedisClient and reuse the returned IClient across
+// requests instead of opening a fresh connection per call.
 type Client struct {
-	conn    IConnection
-	address string
-	mu      sync.Mutex
-	auth    string
-	dialer  IDialer
+	cfg      Config
+	dialer   IDialer
+	topology topology
+
+	mu     sync.RWMutex
+	pools  map[string]*connPool
+	master string // resolved master address, single and sentinel topologies
+	router *clusterRouter
 }
 
-func NewRedisClient(address string, auth string) (IClient, error) {
+func NewRedisClient(cfg Config) (IClient, error) {
+	if len(cfg.Addresses) == 0 {
+		return nil, errors.New("resp: at least one address is required")
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = DefaultPoolSize
+	}
+
 	client := &Client{
-		address: address,
-		auth:    auth,
-		dialer:  NewDialer(),
+		cfg:    cfg,
+		dialer: NewDialer(),
+		pools:  make(map[string]*connPool),
+	}
+	if cfg.TLS != nil {
+		client.dialer = NewTLSDialer(cfg.TLS)
+	}
+
+	switch {
+	case cfg.SentinelMasterSet != "":
+		client.topology = topologySentinel
+		master, err := DiscoverMaster(context.Background(), client.dialer, cfg)
+		if err != nil {
+			return nil, err
+		}
+		client.master = master
+	case len(cfg.Addresses) > 1:
+		client.topology = topologyCluster
+		client.router = newClusterRouter(cfg.Addresses)
+	default:
+		client.topology = topologySingle
+		client.master = cfg.Addresses[0]
+	}
+
+	return client, nil
+}
+
+// addressFor resolves which node a command touching key should go to.
+func (client *Client) addressFor(key string) string {
+	switch client.topology {
+	case topologyCluster:
+		return client.router.addressForKey(key)
+	default:
+		client.mu.RLock()
+		defer client.mu.RUnlock()
+		return client.master
 	}
+}
 
-	conn, err := NewRedisConnection(client.dialer, address, auth)
+func (client *Client) poolFor(address string) *connPool {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	pool, ok := client.pools[address]
+	if !ok {
+		pool = newConnPool(client.cfg.PoolSize, func(ctx context.Context) (IConnection, error) {
+			conn, err := NewRedisConnection(client.dialer, address, client.cfg.Username, client.cfg.Password)
+			if err != nil {
+				return nil, err
+			}
+			if client.cfg.DB != 0 {
+				if err := selectDB(ctx, conn, client.cfg.DB); err != nil {
+					_ = conn.Close()
+					return nil, err
+				}
+			}
+			return conn, nil
+		})
+		client.pools[address] = pool
+	}
+	return pool
+}
+
+// selectDB switches conn to the given logical database with SELECT, as used
+// by non-cluster Redis deployments that partition keys across databases.
+func selectDB(ctx context.Context, conn IConnection, db int) error {
+	cmd := encodeCommand("SELECT", strconv.Itoa(db))
+	if err := conn.Send(ctx, cmd); err != nil {
+		return err
+	}
+	reply, err := conn.Receive(ctx)
 	if err != nil {
-		return nil, errors.New("can't create redis connection")
+		return err
 	}
+	if err := reply.Err(); err != nil {
+		return err
+	}
+	if reply.String() != "OK" {
+		return fmt.Errorf("select: unexpected response from server %s", reply.String())
+	}
+	return nil
+}
 
-	client.conn = conn
+// redialMaster re-runs Sentinel discovery and swaps in the new master
+// address, used when a pooled connection to the previous master fails.
+func (client *Client) redialMaster(ctx context.Context) (string, error) {
+	master, err := DiscoverMaster(ctx, client.dialer, client.cfg)
+	if err != nil {
+		return "", err
+	}
+	client.mu.Lock()
+	client.master = master
+	client.mu.Unlock()
+	return master, nil
+}
 
-	return client, nil
+// acquire returns a pooled connection for the node responsible for key,
+// retrying Sentinel discovery once if the current master's pool can't hand
+// out a connection.
+func (client *Client) acquire(ctx context.Context, key string) (IConnection, *connPool, error) {
+	return client.acquireAddress(ctx, client.addressFor(key))
+}
+
+// acquireAddress returns a pooled connection for address directly, bypassing
+// key-based routing - used for commands that must target a specific node
+// rather than whichever node a key happens to hash to.
+func (client *Client) acquireAddress(ctx context.Context, address string) (IConnection, *connPool, error) {
+	pool := client.poolFor(address)
+
+	conn, err := pool.Get(ctx)
+	if err != nil && client.topology == topologySentinel {
+		if newAddress, derr := client.redialMaster(ctx); derr == nil {
+			pool = client.poolFor(newAddress)
+			conn, err = pool.Get(ctx)
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return conn, pool, nil
 }
 
-func (client *Client) Do(ctx context.Context, command string) (string, error) {
+// nodeAddresses returns every node this client talks to: every configured
+// node in Cluster topology, or just the current master/single instance
+// otherwise. Used by commands like KEYS that must be fanned out to every
+// node instead of routed by a single key.
+func (client *Client) nodeAddresses() []string {
+	if client.topology == topologyCluster {
+		return client.router.addresses
+	}
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+	return []string{client.master}
+}
+
+func (client *Client) Do(ctx context.Context, key string, command string) (*Reply, error) {
+	conn, pool, err := client.acquire(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return sendReceive(ctx, conn, pool, command)
+}
+
+// doAddress behaves like Do, but targets address directly instead of
+// routing by key.
+func (client *Client) doAddress(ctx context.Context, address string, command string) (*Reply, error) {
+	conn, pool, err := client.acquireAddress(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	return sendReceive(ctx, conn, pool, command)
+}
+
+// sendReceive sends command over conn and waits for its reply, returning
+// conn to pool on success and discarding it (so a connection left in an
+// unknown state is never reused) on error or cancellation.
+func sendReceive(ctx context.Context, conn IConnection, pool *connPool, command string) (*Reply, error) {
 	errChan := make(chan error, 1)
-	replyChan := make(chan string, 1)
+	replyChan := make(chan *Reply, 1)
 	go func() {
-		err := client.conn.Send(ctx, command)
-		if err != nil {
+		if err := conn.Send(ctx, command); err != nil {
 			errChan <- err
 			return
 		}
-
-		reply, err := client.conn.Receive(ctx)
+		reply, err := conn.Receive(ctx)
 		if err != nil {
 			errChan <- err
-		} else {
-			replyChan <- reply
+			return
 		}
+		replyChan <- reply
 	}()
 
 	select {
 	case <-ctx.Done():
-		return "", ctx.Err() // The context was cancelled
+		pool.Discard(conn)
+		return nil, ctx.Err()
 	case err := <-errChan:
-		return "", err // The redis operation returned an error
+		pool.Discard(conn) // connection state is unknown after an error, don't reuse it
+		return nil, err
 	case reply := <-replyChan:
-		return reply, nil // The redis operation was successful
+		pool.Put(conn)
+		return reply, nil
+	}
+}
+
+// Pipeline acquires a connection for key and wraps it in a Pipeline. The
+// connection is returned to (or evicted from) the pool when Pipeline.Exec
+// runs.
+func (client *Client) Pipeline(ctx context.Context, key string) (*Pipeline, error) {
+	conn, pool, err := client.acquire(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return newPipeline(conn, func(conn IConnection, err error) {
+		if err != nil {
+			pool.Discard(conn)
+			return
+		}
+		pool.Put(conn)
+	}), nil
+}
+
+// Transaction runs commands atomically by wrapping them in MULTI/EXEC over
+// a single pipelined connection, and returns each command's reply in the
+// order queued.
+func (client *Client) Transaction(ctx context.Context, key string, commands ...string) ([]*Reply, error) {
+	pipeline, err := client.Pipeline(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	pipeline.Queue(encodeCommand("MULTI"))
+	for _, cmd := range commands {
+		pipeline.Queue(cmd)
 	}
+	pipeline.Queue(encodeCommand("EXEC"))
 
+	replies, err := pipeline.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(replies) == 0 {
+		return nil, errors.New("resp: transaction returned no replies")
+	}
+	exec := replies[len(replies)-1]
+	if err := exec.Err(); err != nil {
+		return nil, err
+	}
+	if exec.Type != Array {
+		return nil, fmt.Errorf("resp: unexpected EXEC reply %s", exec.String())
+	}
+	return exec.Elems, nil
 }
 
 func (client *Client) Ping(ctx context.Context) (string, error) {
-	response, err := client.Do(ctx, PingCmd)
+	reply, err := client.Do(ctx, "", PingCmd)
 	if err != nil {
 		return "", err
 	}
-	if response != "PONG" {
+	if err := reply.Err(); err != nil {
+		return "", err
+	}
+	if reply.String() != "PONG" {
 		return "", errors.New("unexpected response from server")
 	}
-	return response, nil
+	return reply.String(), nil
 }
 
 func (client *Client) Set(ctx context.Context, key string, value string) error {
 	cmd := fmt.Sprintf(SendCmd, len(key), key, len(value), value)
-	response, err := client.Do(ctx, cmd)
+	reply, err := client.Do(ctx, key, cmd)
 	if err != nil {
 		return err
 	}
-	if response != "OK" {
-		return fmt.Errorf("set: unexpected response from server %s", response)
+	if err := reply.Err(); err != nil {
+		return err
+	}
+	if reply.String() != "OK" {
+		return fmt.Errorf("set: unexpected response from server %s", reply.String())
 	}
 	return nil
 }
 
 func (client *Client) Incr(ctx context.Context, key string) (int, error) {
 	cmd := fmt.Sprintf(IncrCmd, len(key), key)
-	response, err := client.Do(ctx, cmd)
+	reply, err := client.Do(ctx, key, cmd)
 	if err != nil {
 		return 0, err
 	}
-
-	// Parse the response => should be in the format: ":<number>\r\n" for a successful INCR command
-	var newValue int
-	if _, err := fmt.Sscanf(response, ":%d\r\n", &newValue); err != nil {
-		return 0, fmt.Errorf("incr: unexpected response from server %s", response)
+	if err := reply.Err(); err != nil {
+		return 0, err
 	}
-
-	// Return the new value
-	return newValue, nil
+	if reply.Type != Integer {
+		return 0, fmt.Errorf("incr: unexpected response from server %s", reply.String())
+	}
+	return int(reply.Int), nil
 }
 
 func (client *Client) Expire(ctx context.Context, key string, seconds int) (bool, error) {
 	cmd := fmt.Sprintf(ExpireCmd, len(key), key, len(fmt.Sprintf("%d", seconds)), seconds)
-	response, err := client.Do(ctx, cmd)
+	reply, err := client.Do(ctx, key, cmd)
 	if err != nil {
 		return false, err
 	}
-
-	// Parse the response => should be in the format: ":1" for a successful EXPIRE command (if the key exists), or ":0" if it does not.
-	if response == ":1" {
+	if err := reply.Err(); err != nil {
+		return false, err
+	}
+	if reply.Type != Integer {
+		return false, fmt.Errorf("expire: unexpected response from server %s", reply.String())
+	}
+	// 1 if the key's expiry was set, 0 if the key does not exist.
+	switch reply.Int {
+	case 1:
 		return true, nil
-	} else if response == ":0" {
+	case 0:
 		return false, nil
-	} else {
-		return false, fmt.Errorf("expire: unexpected response from server %s", response)
+	default:
+		return false, fmt.Errorf("expire: unexpected response from server %s", reply.String())
 	}
 }
 
 func (client *Client) SetWithTTL(ctx context.Context, key string, value string, ttl int) error {
 	cmd := fmt.Sprintf(SetWithTTLCmd, len(key), key, len(value), value, len(strconv.Itoa(ttl)), ttl)
-	response, err := client.Do(ctx, cmd)
+	reply, err := client.Do(ctx, key, cmd)
 	if err != nil {
 		return err
 	}
-	if response != "OK" {
-		return fmt.Errorf("setWithTTL: unexpected response from server %s", response)
+	if err := reply.Err(); err != nil {
+		return err
+	}
+	if reply.String() != "OK" {
+		return fmt.Errorf("setWithTTL: unexpected response from server %s", reply.String())
 	}
 	return nil
 }
 
 func (client *Client) Get(ctx context.Context, key string) (string, error) {
 	cmd := fmt.Sprintf(GetCmd, len(key), key)
-	response, err := client.Do(ctx, cmd)
+	reply, err := client.Do(ctx, key, cmd)
 	if err != nil {
 		return "", err
 	}
-	return response, nil
+	if err := reply.Err(); err != nil {
+		return "", err
+	}
+	return reply.String(), nil
+}
+
+// MGet fetches several keys in a single round trip. Missing keys come back
+// as empty strings, same as Get does for a single missing key.
+func (client *Client) MGet(ctx context.Context, keys ...string) ([]string, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	cmd := encodeCommand(append([]string{"MGET"}, keys...)...)
+	reply, err := client.Do(ctx, keys[0], cmd)
+	if err != nil {
+		return nil, err
+	}
+	if err := reply.Err(); err != nil {
+		return nil, err
+	}
+	return reply.Strings()
+}
+
+// MSet writes several keys in a single round trip. All values are routed to
+// the node owning the first key iterated, so in cluster mode the caller is
+// responsible for keeping the keys on the same hash slot (e.g. via a "{tag}"
+// hash tag) if they must land on one node together.
+func (client *Client) MSet(ctx context.Context, values map[string]string) error {
+	if len(values) == 0 {
+		return nil
+	}
+	args := make([]string, 0, len(values)*2+1)
+	args = append(args, "MSET")
+	var routingKey string
+	for key, value := range values {
+		if routingKey == "" {
+			routingKey = key
+		}
+		args = append(args, key, value)
+	}
+	reply, err := client.Do(ctx, routingKey, encodeCommand(args...))
+	if err != nil {
+		return err
+	}
+	if err := reply.Err(); err != nil {
+		return err
+	}
+	if reply.String() != "OK" {
+		return fmt.Errorf("mset: unexpected response from server %s", reply.String())
+	}
+	return nil
 }
 
 func (client *Client) Delete(ctx context.Context, key string) error {
 	cmd := fmt.Sprintf(DeleteCmd, len(key), key)
-	response, err := client.Do(ctx, cmd)
+	reply, err := client.Do(ctx, key, cmd)
 	if err != nil {
 		return err
 	}
-	// ":1" for successful deletion of one key.
-	// ":0" If the key does not exist
-	if response != ":1" && response != ":0" {
-		return fmt.Errorf("delete: unexpected response from server %s", response)
+	if err := reply.Err(); err != nil {
+		return err
+	}
+	// 1 for successful deletion of one key, 0 if the key does not exist.
+	if reply.Type != Integer || (reply.Int != 0 && reply.Int != 1) {
+		return fmt.Errorf("delete: unexpected response from server %s", reply.String())
 	}
+	return nil
+}
 
+func (client *Client) Unlink(ctx context.Context, key string) error {
+	cmd := fmt.Sprintf(UnlinkCmd, len(key), key)
+	reply, err := client.Do(ctx, key, cmd)
+	if err != nil {
+		return err
+	}
+	if err := reply.Err(); err != nil {
+		return err
+	}
+	if reply.Type != Integer || (reply.Int != 0 && reply.Int != 1) {
+		return fmt.Errorf("unlink: unexpected response from server %s", reply.String())
+	}
 	return nil
 }
 
+// Keys resolves pattern against every node this client talks to and returns
+// the union of matches. A pattern isn't routable by hash slot like a normal
+// key, so in Cluster topology it has to be fanned out to each node in turn -
+// otherwise keys living on every node but the one a single lookup happened
+// to hit would never be found (and, for invalidation callers, never evicted).
+//
+// Each node is walked with cursor-based SCAN rather than KEYS: KEYS is
+// O(N) on the keyspace and blocks that node for the whole scan, which would
+// stall every other client sharing it for however long a large pattern
+// match takes.
+func (client *Client) Keys(ctx context.Context, pattern string) ([]string, error) {
+	var keys []string
+	for _, address := range client.nodeAddresses() {
+		nodeKeys, err := client.scanKeys(ctx, address, pattern)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, nodeKeys...)
+	}
+	return keys, nil
+}
+
+// scanKeys resolves pattern on a single node via SCAN, paging through the
+// keyspace in COUNT-sized steps instead of KEYS's single all-at-once pass.
+func (client *Client) scanKeys(ctx context.Context, address string, pattern string) ([]string, error) {
+	var keys []string
+	cursor := "0"
+	for {
+		cmd := encodeCommand("SCAN", cursor, "MATCH", pattern, "COUNT", "1000")
+		reply, err := client.doAddress(ctx, address, cmd)
+		if err != nil {
+			return nil, err
+		}
+		if err := reply.Err(); err != nil {
+			return nil, err
+		}
+		if reply.Type != Array || len(reply.Elems) != 2 {
+			return nil, fmt.Errorf("scan: unexpected reply %s", reply.String())
+		}
+		cursor = reply.Elems[0].String()
+		pageKeys, err := reply.Elems[1].Strings()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, pageKeys...)
+		if cursor == "0" {
+			return keys, nil
+		}
+	}
+}
+
+func (client *Client) Publish(ctx context.Context, channel string, message string) error {
+	cmd := encodeCommand("PUBLISH", channel, message)
+	reply, err := client.Do(ctx, channel, cmd)
+	if err != nil {
+		return err
+	}
+	return reply.Err()
+}
+
 func (client *Client) Close() error {
 	client.mu.Lock()
 	defer client.mu.Unlock()
-	return client.conn.Close()
+	var firstErr error
+	for _, pool := range client.pools {
+		if err := pool.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
 }
@@ -0,0 +1,38 @@
+package resp
+
+const clusterSlotCount = 16384
+
+// clusterRouter maps keys to the cluster node that owns their hash slot.
+//
+// It doesn't learn real slot ownership via CLUSTER SHARDS/SLOTS and doesn't
+// follow MOVED redirects; it splits the slot range into equal contiguous
+// ranges across the configured addresses, in the order given. A key whose
+// slot the chosen node doesn't actually own gets that node's MOVED error
+// reply.
+type clusterRouter struct {
+	addresses    []string
+	slotsPerNode int
+}
+
+func newClusterRouter(addresses []string) *clusterRouter {
+	nodeCount := len(addresses)
+	if nodeCount == 0 {
+		return &clusterRouter{}
+	}
+	return &clusterRouter{
+		addresses:    addresses,
+		slotsPerNode: (clusterSlotCount + nodeCount - 1) / nodeCount,
+	}
+}
+
+// addressForKey returns the node address responsible for key.
+func (r *clusterRouter) addressForKey(key string) string {
+	if len(r.addresses) == 0 {
+		return ""
+	}
+	idx := hashSlot(key) / r.slotsPerNode
+	if idx >= len(r.addresses) {
+		idx = len(r.addresses) - 1
+	}
+	return r.addresses[idx]
+}
@@ -0,0 +1,39 @@
+package resp
+
+import "crypto/tls"
+
+// DefaultPoolSize is the number of pooled connections kept per Redis node
+// when Config.PoolSize is left unset.
+const DefaultPoolSize = 10
+
+// Config describes how to reach a Redis deployment: a single instance, a
+// Sentinel-managed master/replica set, or a Redis Cluster.
+//
+// Exactly one topology is selected based on the fields that are set:
+//   - SentinelMasterSet non-empty: Addresses are treated as Sentinel
+//     endpoints and the master is discovered via SENTINEL
+//     get-master-addr-by-name.
+//   - len(Addresses) > 1 and SentinelMasterSet is empty: Addresses are
+//     treated as Redis Cluster nodes and keys are routed by hash slot.
+//   - otherwise: Addresses[0] is dialed directly as a single instance.
+type Config struct {
+	// Addresses are the host:port pairs to connect to. Their meaning
+	// depends on the topology, see above.
+	Addresses []string
+	// SentinelMasterSet is the name Sentinel uses to track the master
+	// for this deployment (the "master name" in Sentinel terms).
+	SentinelMasterSet string
+	// SentinelPassword authenticates against the Sentinel instances
+	// themselves, as opposed to the Redis master/replicas they track.
+	SentinelPassword string
+	// Username authenticates via Redis 6+ ACL AUTH. Left empty, AUTH is
+	// sent in its legacy password-only form.
+	Username string
+	Password string
+	// DB selects the logical database with SELECT after connecting.
+	DB int
+	// PoolSize bounds the number of pooled connections kept per node.
+	// Defaults to DefaultPoolSize.
+	PoolSize int
+	TLS      *tls.Config
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"strconv"
 	"strings"
@@ -12,10 +13,23 @@ import (
 )
 
 type IConnection interface {
-	Auth(ctx context.Context, password string) error
+	// Auth sends AUTH. When username is empty, it sends the legacy
+	// password-only form; otherwise it sends the two-argument Redis 6+ ACL
+	// form "AUTH <username> <password>".
+	Auth(ctx context.Context, username string, password string) error
 	Ping(ctx context.Context) error
 	Send(ctx context.Context, command string) error
-	Receive(ctx context.Context) (string, error)
+	// Receive reads and recursively parses one RESP2 reply: simple string,
+	// error, integer, bulk string, array (of any of the above, including
+	// nested arrays) or nil.
+	Receive(ctx context.Context) (*Reply, error)
+	// ReceiveBlocking reads one reply like Receive, but without Receive's
+	// request-style fallback deadline: it blocks indefinitely for data,
+	// honoring ctx cancellation by closing the connection rather than
+	// timing the read out. Use it for long-lived blocking reads, like
+	// pub/sub, where Receive's 5-second fallback would fire on every idle
+	// period longer than that.
+	ReceiveBlocking(ctx context.Context) (*Reply, error)
 	Close() error
 }
 type Connection struct {
@@ -23,7 +37,7 @@ type Connection struct {
 	rw   *bufio.ReadWriter
 }
 
-func NewRedisConnection(dialer IDialer, address string, auth string) (IConnection, error) {
+func NewRedisConnection(dialer IDialer, address string, username string, password string) (IConnection, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -37,9 +51,9 @@ func NewRedisConnection(dialer IDialer, address string, auth string) (IConnectio
 		rw:   bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn)),
 	}
 
-	if auth != "" {
+	if password != "" {
 		// Authenticate with Redis using the AUTH command
-		if err := rc.Auth(ctx, auth); err != nil {
+		if err := rc.Auth(ctx, username, password); err != nil {
 			_ = conn.Close()
 			return nil, err
 		}
@@ -48,19 +62,28 @@ func NewRedisConnection(dialer IDialer, address string, auth string) (IConnectio
 	return rc, nil
 }
 
-func (rc *Connection) Auth(ctx context.Context, password string) error {
+func (rc *Connection) Auth(ctx context.Context, username string, password string) error {
 	// Check if the context has been canceled before attempting the read operation
 	if err := ctx.Err(); err != nil {
 		return err
 	}
-	if err := rc.Send(ctx, fmt.Sprintf("AUTH %s", password)); err != nil {
+	var cmd string
+	if username != "" {
+		cmd = encodeCommand("AUTH", username, password)
+	} else {
+		cmd = encodeCommand("AUTH", password)
+	}
+	if err := rc.Send(ctx, cmd); err != nil {
 		return err
 	}
 	reply, err := rc.Receive(ctx)
 	if err != nil {
 		return err
 	}
-	if reply != "OK" {
+	if err := reply.Err(); err != nil {
+		return fmt.Errorf("authentication failed: %w", err)
+	}
+	if reply.String() != "OK" {
 		return errors.New("authentication failed")
 	}
 	return nil
@@ -84,7 +107,7 @@ func (rc *Connection) Ping(ctx context.Context) error {
 	}
 
 	// Check if the reply is a valid PONG response
-	if reply != "PONG" {
+	if reply.String() != "PONG" {
 		return errors.New("did not receive PONG response")
 	}
 
@@ -111,9 +134,9 @@ func (rc *Connection) Send(ctx context.Context, command string) error {
 	return rc.rw.Flush()
 }
 
-func (rc *Connection) Receive(ctx context.Context) (string, error) {
+func (rc *Connection) Receive(ctx context.Context) (*Reply, error) {
 	if err := ctx.Err(); err != nil {
-		return "", err
+		return nil, err
 	}
 	deadline, ok := ctx.Deadline()
 	if !ok { // Default deadline if none is set
@@ -121,36 +144,93 @@ func (rc *Connection) Receive(ctx context.Context) (string, error) {
 	}
 
 	if err := rc.conn.SetReadDeadline(deadline); err != nil {
-		return "", err
+		return nil, err
+	}
+
+	return rc.readReply()
+}
+
+// ReceiveBlocking reads one reply with no read deadline at all, unblocking
+// early only if ctx is done - at which point it closes the connection out
+// from under the in-flight read, since net.Conn has no way to cancel a read
+// directly.
+func (rc *Connection) ReceiveBlocking(ctx context.Context) (*Reply, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
+	if err := rc.conn.SetReadDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = rc.conn.Close()
+		case <-done:
+		}
+	}()
 
+	return rc.readReply()
+}
+
+// readReply reads a single RESP2 reply, recursing into readReply once per
+// array element so nested arrays (e.g. MULTI/EXEC results) parse correctly.
+func (rc *Connection) readReply() (*Reply, error) {
 	line, err := rc.rw.ReadString('\n')
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errors.New("empty reply from server")
 	}
+	body := strings.TrimSuffix(strings.TrimSuffix(line[1:], "\n"), "\r")
 
 	switch line[0] {
-	case '-': // Handle simple error
-		return "", fmt.Errorf(strings.TrimSuffix(line[1:], "\r\n"))
-	case '$': //Assume the reply is a bulk string ,array serialization ain't supported in this client
-		length, _ := strconv.Atoi(strings.TrimSuffix(line[1:], "\r\n")) //trim the CRLF from our response
+	case '+':
+		return &Reply{Type: SimpleString, Str: body}, nil
+	case '-':
+		return &Reply{Type: ErrorReply, Str: body}, nil
+	case ':':
+		n, err := strconv.ParseInt(body, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("resp: invalid integer reply %q: %w", body, err)
+		}
+		return &Reply{Type: Integer, Int: n}, nil
+	case '$':
+		length, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("resp: invalid bulk string length %q: %w", body, err)
+		}
 		if length == -1 {
-			// This is a nil reply
-			return "", nil
+			return &Reply{Type: Nil}, nil
+		}
+		buf := make([]byte, length+2) // +2 for the trailing CRLF
+		if _, err := io.ReadFull(rc.rw, buf); err != nil {
+			return nil, err
 		}
-		buf := make([]byte, length+2) // +2 for the CRLF (\r\n)
-		_, err = rc.rw.Read(buf)
+		return &Reply{Type: BulkString, Str: string(buf[:length])}, nil
+	case '*':
+		count, err := strconv.Atoi(body)
 		if err != nil {
-			return "", err
+			return nil, fmt.Errorf("resp: invalid array length %q: %w", body, err)
+		}
+		if count == -1 {
+			return &Reply{Type: Nil}, nil
 		}
-		return string(buf[:length]), nil
-	case '+': // Handle simple string, return the string without the '+' prefix
-		return strings.TrimSuffix(line[1:], "\r\n"), nil
+		elems := make([]*Reply, 0, count)
+		for i := 0; i < count; i++ {
+			elem, err := rc.readReply()
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, elem)
+		}
+		return &Reply{Type: Array, Elems: elems}, nil
 	default:
-		return strings.TrimSuffix(line, "\r\n"), nil
-
+		return nil, fmt.Errorf("resp: unsupported reply prefix %q", line[0])
 	}
-
 }
 
 func (rc *Connection) Close() error {
@@ -0,0 +1,74 @@
+package resp
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+)
+
+// newTestConnection wires a Connection up to one end of an in-memory pipe,
+// returning the other end for the test to write raw RESP2 frames on.
+func newTestConnection(t *testing.T) (*Connection, net.Conn) {
+	t.Helper()
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		_ = server.Close()
+		_ = client.Close()
+	})
+	return &Connection{
+		conn: client,
+		rw:   bufio.NewReadWriter(bufio.NewReader(client), bufio.NewWriter(client)),
+	}, server
+}
+
+func TestConnectionReceive(t *testing.T) {
+	cases := []struct {
+		name string
+		wire string
+		want *Reply
+	}{
+		{"simple string", "+OK\r\n", &Reply{Type: SimpleString, Str: "OK"}},
+		{"error", "-ERR 100% broken\r\n", &Reply{Type: ErrorReply, Str: "ERR 100% broken"}},
+		{"integer", ":42\r\n", &Reply{Type: Integer, Int: 42}},
+		{"bulk string", "$5\r\nhello\r\n", &Reply{Type: BulkString, Str: "hello"}},
+		{"empty bulk string", "$0\r\n\r\n", &Reply{Type: BulkString, Str: ""}},
+		{"nil bulk string", "$-1\r\n", &Reply{Type: Nil}},
+		{"nil array", "*-1\r\n", &Reply{Type: Nil}},
+		{"array", "*2\r\n+one\r\n:2\r\n", &Reply{Type: Array, Elems: []*Reply{
+			{Type: SimpleString, Str: "one"},
+			{Type: Integer, Int: 2},
+		}}},
+		{"nested array", "*2\r\n*1\r\n:1\r\n$3\r\nfoo\r\n", &Reply{Type: Array, Elems: []*Reply{
+			{Type: Array, Elems: []*Reply{{Type: Integer, Int: 1}}},
+			{Type: BulkString, Str: "foo"},
+		}}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			conn, server := newTestConnection(t)
+			go func() { _, _ = server.Write([]byte(tc.wire)) }()
+
+			got, err := conn.Receive(context.Background())
+			if err != nil {
+				t.Fatalf("Receive: %v", err)
+			}
+			if !repliesEqual(got, tc.want) {
+				t.Errorf("Receive() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func repliesEqual(a, b *Reply) bool {
+	if a.Type != b.Type || a.Str != b.Str || a.Int != b.Int || len(a.Elems) != len(b.Elems) {
+		return false
+	}
+	for i := range a.Elems {
+		if !repliesEqual(a.Elems[i], b.Elems[i]) {
+			return false
+		}
+	}
+	return true
+}
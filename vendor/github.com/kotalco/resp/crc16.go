@@ -0,0 +1,42 @@
+package resp
+
+import "strings"
+
+// crc16Table is the CRC16/XMODEM table (polynomial 0x1021) Redis Cluster
+// uses to compute hash slots.
+var crc16Table = func() [256]uint16 {
+	const poly = 0x1021
+	var table [256]uint16
+	for i := 0; i < 256; i++ {
+		crc := uint16(i) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = (crc << 1) ^ poly
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc = (crc << 8) ^ crc16Table[byte(crc>>8)^b]
+	}
+	return crc
+}
+
+// hashSlot returns the Redis Cluster hash slot (0-16383) for key, honoring
+// "{tag}" hash tags so multi-key operations on related keys land on the
+// same node.
+func hashSlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16([]byte(key)) % 16384)
+}
@@ -0,0 +1,39 @@
+package resp
+
+import "testing"
+
+func TestCRC16CheckValue(t *testing.T) {
+	// "123456789" is the standard check value for CRC-16/XMODEM: 0x31C3.
+	got := crc16([]byte("123456789"))
+	want := uint16(0x31C3)
+	if got != want {
+		t.Fatalf("crc16(%q) = %#x, want %#x", "123456789", got, want)
+	}
+}
+
+func TestHashSlotRange(t *testing.T) {
+	for _, key := range []string{"", "foo", "{user1000}.following"} {
+		if slot := hashSlot(key); slot < 0 || slot >= clusterSlotCount {
+			t.Errorf("hashSlot(%q) = %d, want in [0, %d)", key, slot, clusterSlotCount)
+		}
+	}
+}
+
+func TestHashSlotHonorsHashTag(t *testing.T) {
+	a := hashSlot("{user1000}.following")
+	b := hashSlot("{user1000}.followers")
+	if a != b {
+		t.Errorf("hashSlot(%q) = %d, hashSlot(%q) = %d, want equal (same hash tag)",
+			"{user1000}.following", a, "{user1000}.followers", b)
+	}
+}
+
+func TestHashSlotEmptyHashTagUsesWholeKey(t *testing.T) {
+	// "{}" has no content between the braces, so it isn't a valid hash tag -
+	// the whole key, braces included, should be hashed.
+	got := hashSlot("{}foo")
+	want := int(crc16([]byte("{}foo")) % clusterSlotCount)
+	if got != want {
+		t.Errorf("hashSlot(%q) = %d, want %d", "{}foo", got, want)
+	}
+}
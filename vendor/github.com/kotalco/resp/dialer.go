@@ -2,6 +2,7 @@ package resp
 
 import (
 	"context"
+	"crypto/tls"
 	"net"
 )
 
@@ -9,13 +10,27 @@ type IDialer interface {
 	Dial(ctx context.Context, address string) (net.Conn, error)
 }
 
-type Dialer struct{}
+// Dialer dials plain TCP connections. When TLSConfig is non-nil, it dials
+// TLS instead, as required by Redis deployments with in-transit encryption
+// enabled.
+type Dialer struct {
+	TLSConfig *tls.Config
+}
 
 func NewDialer() IDialer {
 	return &Dialer{}
 }
 
+// NewTLSDialer returns a Dialer that establishes TLS connections using cfg.
+func NewTLSDialer(cfg *tls.Config) IDialer {
+	return &Dialer{TLSConfig: cfg}
+}
+
 func (d Dialer) Dial(ctx context.Context, address string) (net.Conn, error) {
+	if d.TLSConfig != nil {
+		tlsDialer := tls.Dialer{Config: d.TLSConfig}
+		return tlsDialer.DialContext(ctx, "tcp", address)
+	}
 	var dialer net.Dialer
 	return dialer.DialContext(ctx, "tcp", address)
 
@@ -0,0 +1,56 @@
+package resp
+
+import (
+	"context"
+	"strings"
+)
+
+// Pipeline batches queued commands into a single write and reads their
+// replies back in order, trading the one-round-trip-per-command cost of
+// Client.Do for one round trip per batch.
+type Pipeline struct {
+	conn     IConnection
+	release  func(conn IConnection, err error)
+	commands []string
+}
+
+func newPipeline(conn IConnection, release func(conn IConnection, err error)) *Pipeline {
+	return &Pipeline{conn: conn, release: release}
+}
+
+// Queue appends a raw RESP command, such as one built by encodeCommand or a
+// *Cmd format string, to the batch.
+func (p *Pipeline) Queue(command string) {
+	p.commands = append(p.commands, command)
+}
+
+// Exec sends every queued command in a single write and returns their
+// replies in the order they were queued. It always releases the underlying
+// connection back to whichever pool it came from (or closes it, on error)
+// before returning.
+func (p *Pipeline) Exec(ctx context.Context) (replies []*Reply, err error) {
+	defer func() { p.release(p.conn, err) }()
+
+	if len(p.commands) == 0 {
+		return nil, nil
+	}
+
+	var batch strings.Builder
+	for _, cmd := range p.commands {
+		batch.WriteString(cmd)
+	}
+	if err = p.conn.Send(ctx, batch.String()); err != nil {
+		return nil, err
+	}
+
+	replies = make([]*Reply, 0, len(p.commands))
+	for range p.commands {
+		var reply *Reply
+		reply, err = p.conn.Receive(ctx)
+		if err != nil {
+			return replies, err
+		}
+		replies = append(replies, reply)
+	}
+	return replies, nil
+}
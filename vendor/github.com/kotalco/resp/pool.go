@@ -0,0 +1,80 @@
+package resp
+
+import "context"
+
+// connPool is a bounded pool of connections to a single Redis node. idle
+// holds connections available for reuse; sem bounds how many connections -
+// idle or checked out - may exist at once to size, so Get blocks for a
+// free slot under load instead of dialing past the configured pool size.
+type connPool struct {
+	dial func(ctx context.Context) (IConnection, error)
+	idle chan IConnection
+	sem  chan struct{}
+}
+
+func newConnPool(size int, dial func(ctx context.Context) (IConnection, error)) *connPool {
+	if size <= 0 {
+		size = DefaultPoolSize
+	}
+	return &connPool{
+		dial: dial,
+		idle: make(chan IConnection, size),
+		sem:  make(chan struct{}, size),
+	}
+}
+
+// Get returns an idle connection if one is available, or dials a new one
+// once a slot under size is free. It blocks until a connection is put back,
+// a slot frees up or ctx is done, rather than dialing past the configured
+// pool size.
+func (p *connPool) Get(ctx context.Context) (IConnection, error) {
+	select {
+	case conn := <-p.idle:
+		return conn, nil
+	default:
+	}
+
+	select {
+	case conn := <-p.idle:
+		return conn, nil
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	conn, err := p.dial(ctx)
+	if err != nil {
+		<-p.sem
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Put returns conn to the pool, or discards it when the pool is already
+// full of idle connections.
+func (p *connPool) Put(conn IConnection) {
+	select {
+	case p.idle <- conn:
+	default:
+		p.Discard(conn)
+	}
+}
+
+// Discard closes conn and frees the slot it held, for callers that know
+// the connection can't be reused (e.g. after an I/O error).
+func (p *connPool) Discard(conn IConnection) {
+	_ = conn.Close()
+	<-p.sem
+}
+
+func (p *connPool) Close() error {
+	for {
+		select {
+		case conn := <-p.idle:
+			_ = conn.Close()
+			<-p.sem
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package resp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// encodeCommand serializes args as a RESP array of bulk strings, the wire
+// format Redis expects for every command. It is the safe alternative to
+// building commands with fmt.Sprintf directly, since bulk strings are
+// length-prefixed and therefore binary- and space-safe.
+func encodeCommand(args ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return b.String()
+}
@@ -0,0 +1,113 @@
+package resp
+
+import (
+	"context"
+	"fmt"
+)
+
+// Message is a single pub/sub delivery. Pattern is only set for messages
+// received through PSubscribe.
+type Message struct {
+	Channel string
+	Pattern string
+	Payload string
+}
+
+// ISubscriber listens for pub/sub messages, the mechanism a fleet of
+// Cache instances use to coordinate invalidation over a shared Redis - the
+// same pattern blocky's Redis integration uses for its sync channel.
+//
+// A Subscriber owns its connection exclusively for as long as it is
+// subscribed: once SUBSCRIBE/PSUBSCRIBE is sent, the connection speaks
+// only the pub/sub protocol until Unsubscribe, so it is never drawn from
+// Client's connection pool.
+type ISubscriber interface {
+	Subscribe(ctx context.Context, channel string) error
+	PSubscribe(ctx context.Context, pattern string) error
+	Receive(ctx context.Context) (Message, error)
+	Unsubscribe(ctx context.Context) error
+	Close() error
+}
+
+type Subscriber struct {
+	conn IConnection
+}
+
+// NewSubscriber dials a dedicated connection for pub/sub use.
+func NewSubscriber(dialer IDialer, address string, username string, password string) (ISubscriber, error) {
+	conn, err := NewRedisConnection(dialer, address, username, password)
+	if err != nil {
+		return nil, err
+	}
+	return &Subscriber{conn: conn}, nil
+}
+
+func (s *Subscriber) Subscribe(ctx context.Context, channel string) error {
+	return s.subscribe(ctx, "SUBSCRIBE", "subscribe", channel)
+}
+
+func (s *Subscriber) PSubscribe(ctx context.Context, pattern string) error {
+	return s.subscribe(ctx, "PSUBSCRIBE", "psubscribe", pattern)
+}
+
+func (s *Subscriber) subscribe(ctx context.Context, cmdName, ackKind, target string) error {
+	if err := s.conn.Send(ctx, encodeCommand(cmdName, target)); err != nil {
+		return err
+	}
+	ackReply, err := s.conn.Receive(ctx)
+	if err != nil {
+		return err
+	}
+	if err := ackReply.Err(); err != nil {
+		return err
+	}
+	reply, err := ackReply.Strings()
+	if err != nil {
+		return err
+	}
+	if len(reply) != 3 || reply[0] != ackKind {
+		return fmt.Errorf("resp: unexpected %s ack %v", ackKind, reply)
+	}
+	return nil
+}
+
+// Receive blocks until a "message" or "pmessage" frame arrives. Unlike a
+// plain Connection.Receive, this never imposes a fallback deadline - it
+// waits as long as ctx allows, which for a long-lived subscription is
+// indefinitely.
+func (s *Subscriber) Receive(ctx context.Context) (Message, error) {
+	frameReply, err := s.conn.ReceiveBlocking(ctx)
+	if err != nil {
+		return Message{}, err
+	}
+	if err := frameReply.Err(); err != nil {
+		return Message{}, err
+	}
+	reply, err := frameReply.Strings()
+	if err != nil {
+		return Message{}, err
+	}
+	switch {
+	case len(reply) == 3 && reply[0] == "message":
+		return Message{Channel: reply[1], Payload: reply[2]}, nil
+	case len(reply) == 4 && reply[0] == "pmessage":
+		return Message{Pattern: reply[1], Channel: reply[2], Payload: reply[3]}, nil
+	default:
+		return Message{}, fmt.Errorf("resp: unexpected pub/sub frame %v", reply)
+	}
+}
+
+func (s *Subscriber) Unsubscribe(ctx context.Context) error {
+	if err := s.conn.Send(ctx, encodeCommand("UNSUBSCRIBE")); err != nil {
+		return err
+	}
+	reply, err := s.conn.Receive(ctx)
+	if err != nil {
+		return err
+	}
+	return reply.Err()
+}
+
+func (s *Subscriber) Close() error {
+	return s.conn.Close()
+}
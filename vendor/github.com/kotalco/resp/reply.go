@@ -0,0 +1,67 @@
+package resp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ReplyType identifies which RESP2 reply variant a Reply holds.
+type ReplyType int
+
+const (
+	SimpleString ReplyType = iota
+	ErrorReply
+	Integer
+	BulkString
+	Array
+	Nil
+)
+
+// Reply is a fully parsed RESP2 reply. Array replies nest further Replies
+// in Elems, which is what lets Connection.Receive understand SENTINEL,
+// pub/sub frames, SCAN, and MULTI/EXEC without each caller hand-rolling its
+// own array parsing.
+type Reply struct {
+	Type  ReplyType
+	Str   string
+	Int   int64
+	Elems []*Reply
+}
+
+// String renders the reply's scalar value: the payload for simple/bulk
+// strings, ":"-prefixed for integers (matching the format callers used to
+// string-match against before Receive was typed), and "" for Nil.
+func (r *Reply) String() string {
+	switch r.Type {
+	case SimpleString, BulkString:
+		return r.Str
+	case Integer:
+		return fmt.Sprintf(":%d", r.Int)
+	default:
+		return ""
+	}
+}
+
+// Strings converts an Array reply into a []string of its elements' scalar
+// values, as used for SCAN, MGET and SENTINEL get-master-addr-by-name.
+func (r *Reply) Strings() ([]string, error) {
+	if r.Type == Nil {
+		return nil, nil
+	}
+	if r.Type != Array {
+		return nil, fmt.Errorf("resp: expected array reply, got type %d", r.Type)
+	}
+	out := make([]string, 0, len(r.Elems))
+	for _, elem := range r.Elems {
+		out = append(out, elem.String())
+	}
+	return out, nil
+}
+
+// Err returns the server error for an Error reply, and nil otherwise.
+func (r *Reply) Err() error {
+	if r.Type != ErrorReply {
+		return nil
+	}
+	return errors.New(r.Str)
+}
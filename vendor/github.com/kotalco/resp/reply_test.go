@@ -0,0 +1,39 @@
+package resp
+
+import "testing"
+
+func TestReplyErrPreservesPercent(t *testing.T) {
+	// A Redis error string is data, not a format string - a literal "%" in
+	// it (not unusual in real error text) must come through unchanged.
+	r := &Reply{Type: ErrorReply, Str: "ERR 100% broken"}
+	err := r.Err()
+	if err == nil || err.Error() != "ERR 100% broken" {
+		t.Fatalf("Err() = %v, want %q", err, "ERR 100% broken")
+	}
+}
+
+func TestReplyStrings(t *testing.T) {
+	r := &Reply{Type: Array, Elems: []*Reply{
+		{Type: BulkString, Str: "a"},
+		{Type: BulkString, Str: "b"},
+	}}
+	got, err := r.Strings()
+	if err != nil {
+		t.Fatalf("Strings: %v", err)
+	}
+	want := []string{"a", "b"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Strings() = %v, want %v", got, want)
+	}
+}
+
+func TestReplyStringsNil(t *testing.T) {
+	r := &Reply{Type: Nil}
+	got, err := r.Strings()
+	if err != nil {
+		t.Fatalf("Strings: %v", err)
+	}
+	if got != nil {
+		t.Errorf("Strings() = %v, want nil", got)
+	}
+}
@@ -0,0 +1,57 @@
+package resp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// DiscoverMaster asks each configured sentinel in turn for the current
+// master address of cfg.SentinelMasterSet, returning the first answer it
+// gets. Sentinels that are unreachable or that don't know the master are
+// skipped in favor of the next one. Exported so callers outside this
+// package (e.g. a long-lived pub/sub subscriber that must reconnect to the
+// current master after a failover) can redo the same discovery Client uses.
+func DiscoverMaster(ctx context.Context, dialer IDialer, cfg Config) (string, error) {
+	var lastErr error
+	for _, sentinelAddr := range cfg.Addresses {
+		address, err := queryMaster(ctx, dialer, sentinelAddr, cfg.SentinelPassword, cfg.SentinelMasterSet)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return address, nil
+	}
+	if lastErr == nil {
+		lastErr = errors.New("resp: no sentinels configured")
+	}
+	return "", fmt.Errorf("resp: could not discover master %q: %w", cfg.SentinelMasterSet, lastErr)
+}
+
+func queryMaster(ctx context.Context, dialer IDialer, sentinelAddr, sentinelPassword, masterSet string) (string, error) {
+	conn, err := NewRedisConnection(dialer, sentinelAddr, "", sentinelPassword)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	cmd := encodeCommand("SENTINEL", "get-master-addr-by-name", masterSet)
+	if err := conn.Send(ctx, cmd); err != nil {
+		return "", err
+	}
+	addrReply, err := conn.Receive(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := addrReply.Err(); err != nil {
+		return "", err
+	}
+	reply, err := addrReply.Strings()
+	if err != nil {
+		return "", err
+	}
+	if len(reply) != 2 {
+		return "", fmt.Errorf("resp: unexpected SENTINEL reply %v", reply)
+	}
+	return reply[0] + ":" + reply[1], nil
+}